@@ -0,0 +1,156 @@
+package negronilogrus
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/urfave/negroni"
+)
+
+// RedactFunc scrubs a captured request or response body before it is
+// attached to the log entry. contentType is the value of the relevant
+// Content-Type header, which callers can use to decide how to parse body
+// (e.g. as JSON) before redacting sensitive fields.
+type RedactFunc func(contentType string, body []byte) []byte
+
+// BodyLogPolicy controls whether and how request/response bodies are
+// captured for logging. A nil policy (the default) disables body capture
+// entirely; request_size and response_size are still recorded regardless.
+type BodyLogPolicy struct {
+	// MaxBytes is the maximum number of body bytes retained for logging.
+	// Bytes beyond this limit are still counted towards request_size /
+	// response_size but are not stored.
+	MaxBytes int64
+
+	// ContentTypes restricts capture to bodies whose Content-Type header
+	// starts with one of these values. An empty slice allows all types.
+	ContentTypes []string
+
+	// Redact, when set, is applied to a captured body before it is added
+	// to the log entry, e.g. to strip an Authorization header value or
+	// sensitive JSON fields.
+	Redact RedactFunc
+}
+
+func (p *BodyLogPolicy) allows(contentType string) bool {
+	if p == nil {
+		return false
+	}
+	if len(p.ContentTypes) == 0 {
+		return true
+	}
+	for _, ct := range p.ContentTypes {
+		if strings.HasPrefix(contentType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *BodyLogPolicy) redact(contentType string, body []byte) []byte {
+	if p == nil || p.Redact == nil {
+		return body
+	}
+	return p.Redact(contentType, body)
+}
+
+// countingReadCloser wraps a request body, counting every byte read through
+// it and, when capture is true, buffering up to policy.MaxBytes of it for
+// later logging.
+type countingReadCloser struct {
+	io.ReadCloser
+	policy  *BodyLogPolicy
+	capture bool
+	size    int64
+	buf     bytes.Buffer
+}
+
+func newCountingReadCloser(rc io.ReadCloser, policy *BodyLogPolicy, capture bool) *countingReadCloser {
+	return &countingReadCloser{ReadCloser: rc, policy: policy, capture: capture}
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.size += int64(n)
+	if c.capture && n > 0 {
+		if remaining := c.policy.MaxBytes - int64(c.buf.Len()); remaining > 0 {
+			if int64(n) > remaining {
+				c.buf.Write(p[:remaining])
+			} else {
+				c.buf.Write(p[:n])
+			}
+		}
+	}
+	return n, err
+}
+
+// countingResponseWriter wraps a negroni.ResponseWriter, buffering up to
+// policy.MaxBytes of the response body for later logging. Response size is
+// already tracked by negroni.ResponseWriter.Size, so this wrapper only
+// exists to capture the body.
+type countingResponseWriter struct {
+	negroni.ResponseWriter
+	policy *BodyLogPolicy
+	buf    bytes.Buffer
+}
+
+func newCountingResponseWriter(rw negroni.ResponseWriter, policy *BodyLogPolicy) *countingResponseWriter {
+	return &countingResponseWriter{ResponseWriter: rw, policy: policy}
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	if n > 0 && c.policy.allows(c.Header().Get("Content-Type")) {
+		if remaining := c.policy.MaxBytes - int64(c.buf.Len()); remaining > 0 {
+			if int64(n) > remaining {
+				c.buf.Write(p[:remaining])
+			} else {
+				c.buf.Write(p[:n])
+			}
+		}
+	}
+	return n, err
+}
+
+// Embedding negroni.ResponseWriter only promotes its own method set, not
+// the extra optional interfaces (http.Hijacker, http.Flusher,
+// http.CloseNotifier, http.Pusher) that a concrete writer underneath it —
+// such as rewrap.go's rewrapResponseWriter — may implement. Forward them
+// explicitly, the same way rewrapResponseWriter itself does, so wrapping a
+// response writer for body capture doesn't silently drop hijack/WebSocket
+// support. As with rewrapResponseWriter, these are unconditional: a type
+// assertion to http.Hijacker/http.Pusher always succeeds here and only
+// the call itself can fail if the underlying writer doesn't support it.
+
+func (c *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("negronilogrus: underlying ResponseWriter does not support http.Hijacker")
+	}
+	return hj.Hijack()
+}
+
+func (c *countingResponseWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *countingResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := c.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+func (c *countingResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := c.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
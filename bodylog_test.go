@@ -0,0 +1,100 @@
+package negronilogrus
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/urfave/negroni"
+)
+
+func TestCountingReadCloserTruncatesAtMaxBytes(t *testing.T) {
+	const body = "0123456789"
+	policy := &BodyLogPolicy{MaxBytes: 4}
+
+	rc := newCountingReadCloser(io.NopCloser(strings.NewReader(body)), policy, true)
+	n, err := io.Copy(io.Discard, rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != int64(len(body)) {
+		t.Fatalf("expected size %d, got %d", len(body), n)
+	}
+	if rc.size != int64(len(body)) {
+		t.Fatalf("expected size field %d, got %d", len(body), rc.size)
+	}
+	if got := rc.buf.String(); got != body[:policy.MaxBytes] {
+		t.Fatalf("expected captured body %q, got %q", body[:policy.MaxBytes], got)
+	}
+}
+
+func TestCountingResponseWriterTruncatesAtMaxBytes(t *testing.T) {
+	const body = "0123456789"
+	policy := &BodyLogPolicy{MaxBytes: 4}
+
+	nrw := negroni.NewResponseWriter(httptest.NewRecorder())
+	rw := newCountingResponseWriter(nrw, policy)
+
+	if _, err := rw.Write([]byte(body)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rw.Size() != len(body) {
+		t.Fatalf("expected Size() %d, got %d", len(body), rw.Size())
+	}
+	if got := rw.buf.String(); got != body[:policy.MaxBytes] {
+		t.Fatalf("expected captured body %q, got %q", body[:policy.MaxBytes], got)
+	}
+}
+
+// TestBodyLogPolicyRedactInvokedWithBodyAndContentType guards against the
+// redaction hook silently not running, or running against the wrong
+// content type/body, by driving it through Middleware.ServeHTTP end to
+// end and inspecting what actually reached the completion log entry.
+func TestBodyLogPolicyRedactInvokedWithBodyAndContentType(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	var gotContentType string
+	var gotBody []byte
+
+	m := NewMiddlewareFromLogger(logger, "web")
+	m.BodyLogPolicy = &BodyLogPolicy{
+		MaxBytes: 1024,
+		Redact: func(contentType string, body []byte) []byte {
+			gotContentType = contentType
+			gotBody = append([]byte(nil), body...)
+			return []byte(`{"authorization":"[REDACTED]"}`)
+		},
+	}
+
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte(`{"authorization":"secret-token"}`))
+	})
+
+	n := negroni.New(m, handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(`{"authorization":"request-secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	n.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotContentType != "application/json" {
+		t.Fatalf("expected Redact to see response Content-Type %q, got %q", "application/json", gotContentType)
+	}
+	if string(gotBody) != `{"authorization":"secret-token"}` {
+		t.Fatalf("expected Redact to see the captured response body, got %q", gotBody)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected a completion log entry")
+	}
+	if got := entry.Data["response_body"]; got != `{"authorization":"[REDACTED]"}` {
+		t.Fatalf("expected redacted response_body in the log entry, got %v", got)
+	}
+}
@@ -2,11 +2,23 @@ package negronilogrus
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
+// nullLogger backs the no-op *logrus.Entry returned by Extract and
+// ExtractAny when called on a context that was never touched by
+// Middleware.ServeHTTP, so callers can use them unconditionally without a
+// nil check.
+var nullLogger = func() *logrus.Logger {
+	l := logrus.New()
+	l.Out = io.Discard
+	return l
+}()
+
 type ctxLoggerMarker struct{}
 
 type ctxWriterKey struct{}
@@ -14,6 +26,8 @@ type ctxWriterKey struct{}
 
 type ctxLogger struct {
 	logger *logrus.Entry
+
+	mu     sync.Mutex
 	fields logrus.Fields
 }
 
@@ -31,16 +45,43 @@ func Extract(ctx context.Context) *logrus.Entry {
 		return logrus.NewEntry(nullLogger)
 	}
 
+	l.mu.Lock()
 	fields := logrus.Fields{}
-
-	// Add logrus fields added until now.
 	for k, v := range l.fields {
 		fields[k] = v
 	}
+	l.mu.Unlock()
 
 	return l.logger.WithFields(fields)
 }
 
+// AddField adds a single key/value pair to the call-scoped logger's
+// fields, so it is included in the completion log entry produced by
+// Middleware.After. It is a no-op if ctx wasn't produced by
+// Middleware.ServeHTTP.
+func AddField(ctx context.Context, key string, value interface{}) {
+	AddFields(ctx, logrus.Fields{key: value})
+}
+
+// AddFields merges fields into the call-scoped logger's fields, so they
+// are included in the completion log entry produced by Middleware.After.
+// This lets downstream handlers enrich the access log with data they
+// compute mid-request (user id, tenant, cache hit, ...) without wrapping
+// the logger at every call site. It is a no-op if ctx wasn't produced by
+// Middleware.ServeHTTP.
+func AddFields(ctx context.Context, fields logrus.Fields) {
+	l, ok := ctx.Value(ctxLoggerKey).(*ctxLogger)
+	if !ok || l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, v := range fields {
+		l.fields[k] = v
+	}
+}
+
 // ToContext adds the logrus.Entry to the context for extraction later.
 // Returning the new context that has been created.
 func ToContext(ctx context.Context, entry *logrus.Entry) context.Context {
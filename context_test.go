@@ -0,0 +1,101 @@
+package negronilogrus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/urfave/negroni"
+)
+
+func TestAddFieldAddsSingleField(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	ctx := ToContext(context.Background(), entry)
+
+	AddField(ctx, "user_id", "u-1")
+
+	got := Extract(ctx)
+	if got.Data["user_id"] != "u-1" {
+		t.Fatalf("expected user_id=u-1, got %v", got.Data)
+	}
+}
+
+func TestAddFieldsMergesMultipleFields(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	ctx := ToContext(context.Background(), entry)
+
+	AddFields(ctx, logrus.Fields{"tenant": "acme", "cache_hit": true})
+	AddFields(ctx, logrus.Fields{"db_queries": 3})
+
+	got := Extract(ctx).Data
+	if got["tenant"] != "acme" || got["cache_hit"] != true || got["db_queries"] != 3 {
+		t.Fatalf("expected all merged fields present, got %v", got)
+	}
+}
+
+func TestAddFieldsNoopWithoutMiddlewareContext(t *testing.T) {
+	// ctx was never passed through ToContext; AddFields must be a no-op,
+	// not a panic, so callers can use it unconditionally.
+	AddFields(context.Background(), logrus.Fields{"x": 1})
+}
+
+// TestAddFieldsConcurrentWithExtract guards against data races between
+// AddFields, called from a downstream handler goroutine, and Extract,
+// called by Middleware.ServeHTTP's completion logger — the same class of
+// bug TestMiddlewareTimeoutDiscardsLateWrites covers for the response
+// writer side.
+func TestAddFieldsConcurrentWithExtract(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	ctx := ToContext(context.Background(), entry)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			AddField(ctx, "iteration", i)
+		}(i)
+		go func() {
+			defer wg.Done()
+			Extract(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// TestMiddlewareAddFieldsSurvivesTimeout exercises AddFields from inside a
+// handler that keeps running past Middleware.Timeout, mirroring
+// TestMiddlewareTimeoutDiscardsLateWrites but for context fields instead
+// of response writes: ServeHTTP only builds the completion entry after
+// the handler goroutine has fully returned, so fields added up to that
+// point must appear in the completed log line with no race.
+func TestMiddlewareAddFieldsSurvivesTimeout(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	m := NewMiddlewareFromLogger(logger, "web")
+	m.Timeout = 10 * time.Millisecond
+
+	handlerDone := make(chan struct{})
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		defer close(handlerDone)
+		time.Sleep(30 * time.Millisecond)
+		AddField(r.Context(), "late_field", "set-after-deadline")
+	})
+
+	n := negroni.New(m, handler)
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	<-handlerDone
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected a completion log entry")
+	}
+	if entry.Data["late_field"] != "set-after-deadline" {
+		t.Fatalf("expected late_field to reach the completion entry, got %v", entry.Data)
+	}
+}
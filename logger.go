@@ -0,0 +1,76 @@
+package negronilogrus
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the minimal structured logging surface shared by the
+// logrus-backed Middleware and the slog-backed slogmiddleware.Middleware.
+// Code that only needs to log or attach fields can depend on Logger
+// instead of a concrete backend, and retrieve whichever flavor is
+// configured for the current request via ExtractAny.
+type Logger interface {
+	// With returns a Logger with the given key/value pairs (alternating
+	// key, value, key, value, ...) attached as fields.
+	With(args ...interface{}) Logger
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// AsLogger adapts a *logrus.Entry to the backend-agnostic Logger
+// interface.
+func AsLogger(entry *logrus.Entry) Logger {
+	return logrusLogger{entry: entry}
+}
+
+func (l logrusLogger) With(args ...interface{}) Logger {
+	fields := logrus.Fields{}
+	for i := 0; i+1 < len(args); i += 2 {
+		if key, ok := args[i].(string); ok {
+			fields[key] = args[i+1]
+		}
+	}
+	return logrusLogger{entry: l.entry.WithFields(fields)}
+}
+
+func (l logrusLogger) Info(msg string, args ...interface{}) {
+	l.With(args...).(logrusLogger).entry.Info(msg)
+}
+
+func (l logrusLogger) Warn(msg string, args ...interface{}) {
+	l.With(args...).(logrusLogger).entry.Warn(msg)
+}
+
+func (l logrusLogger) Error(msg string, args ...interface{}) {
+	l.With(args...).(logrusLogger).entry.Error(msg)
+}
+
+type ctxAnyLoggerMarker struct{}
+
+var ctxAnyLoggerKey = &ctxAnyLoggerMarker{}
+
+// ToContextAny stores a backend-agnostic Logger in ctx. Both Middleware
+// and slogmiddleware.Middleware call this alongside their backend-specific
+// ToContext, so downstream handlers can fetch whichever flavor is in play
+// via ExtractAny without knowing which one was configured.
+func ToContextAny(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxAnyLoggerKey, l)
+}
+
+// ExtractAny returns the backend-agnostic Logger stored by ToContextAny.
+// If none was stored, a no-op Logger is returned so it's always safe to
+// call.
+func ExtractAny(ctx context.Context) Logger {
+	l, ok := ctx.Value(ctxAnyLoggerKey).(Logger)
+	if !ok || l == nil {
+		return AsLogger(logrus.NewEntry(nullLogger))
+	}
+	return l
+}
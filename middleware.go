@@ -1,9 +1,13 @@
 package negronilogrus
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -33,14 +37,47 @@ type Middleware struct {
 	// Name is the name of the application as recorded in latency metrics
 	Name   string
 	Before func(*logrus.Entry, *http.Request, string) *logrus.Entry
-	After  func(*logrus.Entry, negroni.ResponseWriter, time.Duration, string) *logrus.Entry
+	After  func(*logrus.Entry, negroni.ResponseWriter, time.Duration, string, RequestInfo) *logrus.Entry
+
+	// BodyLogPolicy controls whether request/response bodies are captured
+	// and attached to the completion log entry. A nil policy (the
+	// default) disables body capture but request_size/response_size are
+	// still recorded.
+	BodyLogPolicy *BodyLogPolicy
+
+	// Timeout, when non-zero, is the deadline set on the request context
+	// passed to the next handler in the chain. Once it elapses, the
+	// request context is canceled, a 503 is written if nothing has been
+	// written yet, and the completion log entry's
+	// RequestInfo.DeadlineExceeded is set. The handler itself is not
+	// stopped: ServeHTTP still waits for it to return before logging.
+	Timeout time.Duration
+
+	// StacktracePred decides, from the final response status, whether a
+	// stack trace should be attached to the completion log entry. A
+	// panic always attaches its stack regardless of this predicate. The
+	// default, DefaultStacktracePred, attaches stacks for 5xx responses.
+	StacktracePred func(status int) bool
+
+	// SamplingPolicy, when set, is evaluated before Before runs to decide
+	// whether this request should be logged at all and at what level.
+	// Requests it drops still run through the rest of the chain (body
+	// capture, timeout, panic recovery); only the "started"/"completed"
+	// log lines are suppressed.
+	SamplingPolicy SamplingPolicy
+
+	// CompletionLevel, when set, overrides the level of the "completed
+	// handling request" line based on the final response status, e.g.
+	// StatusLevel. SamplingPolicy still decides whether the line is
+	// logged at all.
+	CompletionLevel func(status int) logrus.Level
 
 	logStarting bool
 
 	clock timer
 
-	// Exclude URLs from logging
-	excludeURLs []string
+	// excludeURLs are checked before any logging occurs.
+	excludeURLs []excludeRule
 }
 
 // NewMiddleware returns a new *Middleware, yay!
@@ -60,8 +97,9 @@ func NewCustomMiddleware(level logrus.Level, formatter logrus.Formatter, name st
 		Before: DefaultBefore,
 		After:  DefaultAfter,
 
-		logStarting: true,
-		clock:       &realClock{},
+		StacktracePred: DefaultStacktracePred,
+		logStarting:    true,
+		clock:          &realClock{},
 	}
 }
 
@@ -73,8 +111,9 @@ func NewMiddlewareFromLogger(logger *logrus.Logger, name string) *Middleware {
 		Before: DefaultBefore,
 		After:  DefaultAfter,
 
-		logStarting: true,
-		clock:       &realClock{},
+		StacktracePred: DefaultStacktracePred,
+		logStarting:    true,
+		clock:          &realClock{},
 	}
 }
 
@@ -84,18 +123,44 @@ func (m *Middleware) SetLogStarting(v bool) {
 	m.logStarting = v
 }
 
-// ExcludeURL adds a new URL u to be ignored during logging. The URL u is parsed, hence the returned error
+// ExcludeURL adds a new URL pattern u to be ignored during logging, for
+// any HTTP method. u may be an exact path, a glob pattern as understood
+// by path.Match (e.g. "/api/*/status"), or, if it starts with "^", an
+// anchored regexp (e.g. "^/api/v[0-9]+/health$"). u is compiled as a
+// regexp or parsed as a URL, hence the returned error.
 func (m *Middleware) ExcludeURL(u string) error {
-	if _, err := url.Parse(u); err != nil {
+	return m.ExcludeURLForMethods(u)
+}
+
+// ExcludeURLForMethods adds a new URL pattern u to be ignored during
+// logging, but only for the given HTTP methods. With no methods given, all
+// methods are excluded. This lets e.g. "/healthz" be silenced for GET
+// while errors on the same path are still logged. u is treated as a
+// regexp (see ExcludeURL) if it starts with "^", else as a path.Match
+// glob; the URL u is parsed in the glob case, hence the returned error.
+func (m *Middleware) ExcludeURLForMethods(u string, methods ...string) error {
+	rule := excludeRule{pattern: u, methods: methods}
+	if strings.HasPrefix(u, "^") {
+		re, err := regexp.Compile(u)
+		if err != nil {
+			return err
+		}
+		rule.re = re
+	} else if _, err := url.Parse(u); err != nil {
 		return err
 	}
-	m.excludeURLs = append(m.excludeURLs, u)
+	m.excludeURLs = append(m.excludeURLs, rule)
 	return nil
 }
 
-// ExcludedURLs returns the list of excluded URLs for this middleware
+// ExcludedURLs returns the list of excluded URL patterns for this
+// middleware.
 func (m *Middleware) ExcludedURLs() []string {
-	return m.excludeURLs
+	patterns := make([]string, len(m.excludeURLs))
+	for i, e := range m.excludeURLs {
+		patterns[i] = e.pattern
+	}
+	return patterns
 }
 
 func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
@@ -107,13 +172,18 @@ func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next htt
 		m.After = DefaultAfter
 	}
 
-	for _, u := range m.excludeURLs {
-		if r.URL.Path == u {
+	for _, e := range m.excludeURLs {
+		if e.matches(r) {
 			next(rw, r)
 			return
 		}
 	}
 
+	doLog, level := true, logrus.InfoLevel
+	if m.SamplingPolicy != nil {
+		doLog, level = m.SamplingPolicy.ShouldLog(r)
+	}
+
 	start := m.clock.Now()
 
 	// Try to get the real IP
@@ -122,6 +192,12 @@ func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next htt
 		remoteAddr = realIP
 	}
 
+	var reqBody *countingReadCloser
+	if r.Body != nil {
+		reqBody = newCountingReadCloser(r.Body, m.BodyLogPolicy, m.BodyLogPolicy.allows(r.Header.Get("Content-Type")))
+		r.Body = reqBody
+	}
+
 	entry := logrus.NewEntry(m.Logger)
 
 	if reqID := r.Header.Get("X-Request-Id"); reqID != "" {
@@ -130,38 +206,144 @@ func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next htt
 
 	entry = m.Before(entry, r, remoteAddr)
 
-	if m.logStarting {
-		entry.Info("started handling request")
+	if m.logStarting && doLog {
+		entry.Log(level, "started handling request")
+	}
+
+	var hijack *rewrapResponseWriter
+	if nrw, ok := rw.(negroni.ResponseWriter); ok {
+		hijack = newRewrapResponseWriter(nrw)
+		rw = hijack
+	}
+
+	var resBody *countingResponseWriter
+	if m.BodyLogPolicy != nil {
+		if nrw, ok := rw.(negroni.ResponseWriter); ok {
+			resBody = newCountingResponseWriter(nrw, m.BodyLogPolicy)
+			rw = resBody
+		}
+	}
+
+	// When Timeout is set, tw becomes the sole writer the handler
+	// goroutine and this goroutine's deadline branch both go through, so
+	// the two can never race on the underlying ResponseWriter (see
+	// timeout.go).
+	var tw *timeoutResponseWriter
+	if m.Timeout > 0 {
+		if nrw, ok := rw.(negroni.ResponseWriter); ok {
+			tw = newTimeoutResponseWriter(nrw)
+			rw = tw
+		}
 	}
 
 	newCtx := ToContext(r.Context(), entry)
-	next(rw, r.WithContext(newCtx))
+	newCtx = ToContextAny(newCtx, AsLogger(entry))
+
+	ctx := newCtx
+	var cancel context.CancelFunc
+	if m.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+
+	var info RequestInfo
+	done := make(chan struct{})
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				info.PanicValue = rec
+				info.Stack = debug.Stack()
+				if res, ok := rw.(negroni.ResponseWriter); ok && !res.Written() {
+					res.WriteHeader(http.StatusInternalServerError)
+				}
+			}
+			close(done)
+		}()
+		next(rw, r.WithContext(ctx))
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			info.DeadlineExceeded = true
+			if tw != nil {
+				tw.timeout(http.StatusServiceUnavailable)
+			} else if res, ok := rw.(negroni.ResponseWriter); ok && !res.Written() {
+				res.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}
+		<-done
+	}
 
 	latency := m.clock.Since(start)
 	res, ok := rw.(negroni.ResponseWriter)
 	if !ok {
-		//ugly hack that will prevent us from merging our changes to the upstream repo!
-		//unfortunately net/http does not come with same intercepting mechanism grpc package offers
-		//so most HTTP handlers use a technique that wraps ResponseWriter with a private structure
-		//to intercept some metrics about the request. For example, there is no way to get the response status code
-		//from the built in ResponseWriter interface so one would need to wrap it as explained here:
-		//https://www.reddit.com/r/golang/comments/7p35s4/how_do_i_get_the_response_status_for_my_middleware/
-		//Unfortunately again, there are as many wrappers as HTTP handlers in the chain and we are at their mercy to
-		//expose the data we need or the original object it wraps...
-		//Our problem is that we are using OpenCensus HTTP Handler to instrument our HTTP server with OpenCensus
-		//and it is f***g dumb! as everyone else it wraps ResponseWriter with a private struct
-		//and it provides no public interface to cast...
-		//So the work around I came up with involves putting the original ResponseWriter
-		//(which happens to be negroni.ResponseWriter) on the request context before calling OpenCensus handler
-		//here we fall back and read it from the context
+		// rewrapResponseWriter above only has something to preserve if rw
+		// was already a negroni.ResponseWriter when ServeHTTP started; it
+		// can't help when an outer middleware earlier in the chain (e.g.
+		// OpenCensus's HTTP handler) has already replaced rw with its own
+		// private wrapper before we ever see it, hiding negroni.ResponseWriter
+		// behind a type we can't assert back to. For that case we rely on
+		// whoever put the real negroni.ResponseWriter on the request context
+		// via AddWriterToContext earlier in the chain, and read it back here.
 		rw  = ExtractWriter(r.Context())
 		res, ok = rw.(negroni.ResponseWriter)
 	}
-	if ok {
+	if !ok {
+		return
+	}
+
+	logCompletion := func() {
+		if info.PanicValue == nil && m.StacktracePred != nil && m.StacktracePred(res.Status()) {
+			info.Stack = debug.Stack()
+		}
+
 		// re-extract logger from newCtx, as it may have extra fields that changed in the holder.
 		log := Extract(newCtx)
-		m.After(log, res, latency, m.Name).Info("completed handling request")
+		entry := m.After(log, res, latency, m.Name, info)
+
+		if reqBody != nil {
+			entry = entry.WithField("request_size", reqBody.size)
+		}
+		entry = entry.WithField("response_size", res.Size())
+
+		if reqBody != nil && reqBody.capture {
+			entry = entry.WithField("request_body", string(m.BodyLogPolicy.redact(r.Header.Get("Content-Type"), reqBody.buf.Bytes())))
+		}
+		if resBody != nil {
+			entry = entry.WithField("response_body", string(m.BodyLogPolicy.redact(res.Header().Get("Content-Type"), resBody.buf.Bytes())))
+		}
+
+		if hijack != nil && hijack.Hijacked() {
+			entry = entry.WithFields(logrus.Fields{
+				"hijacked":       true,
+				"hijacked_bytes": hijack.HijackedBytes(),
+			})
+		}
+
+		if !doLog {
+			return
+		}
+		completionLevel := level
+		if m.CompletionLevel != nil {
+			completionLevel = m.CompletionLevel(res.Status())
+		}
+		entry.Log(completionLevel, "completed handling request")
+	}
+
+	if hijack != nil && hijack.Hijacked() {
+		// Defer the completion line until the hijacked (e.g. WebSocket)
+		// connection actually closes, instead of logging status=0 right
+		// after the upgrade.
+		go func() {
+			<-hijack.Closed()
+			logCompletion()
+		}()
+		return
 	}
+
+	logCompletion()
 }
 
 // BeforeFunc is the func type used to modify or replace the *logrus.Entry prior
@@ -170,7 +352,7 @@ type BeforeFunc func(*logrus.Entry, *http.Request, string) *logrus.Entry
 
 // AfterFunc is the func type used to modify or replace the *logrus.Entry after
 // calling the next func in the middleware chain
-type AfterFunc func(*logrus.Entry, negroni.ResponseWriter, time.Duration, string) *logrus.Entry
+type AfterFunc func(*logrus.Entry, negroni.ResponseWriter, time.Duration, string, RequestInfo) *logrus.Entry
 
 // DefaultBefore is the default func assigned to *Middleware.Before
 func DefaultBefore(entry *logrus.Entry, req *http.Request, remoteAddr string) *logrus.Entry {
@@ -182,11 +364,23 @@ func DefaultBefore(entry *logrus.Entry, req *http.Request, remoteAddr string) *l
 }
 
 // DefaultAfter is the default func assigned to *Middleware.After
-func DefaultAfter(entry *logrus.Entry, res negroni.ResponseWriter, latency time.Duration, name string) *logrus.Entry {
-	return entry.WithFields(logrus.Fields{
+func DefaultAfter(entry *logrus.Entry, res negroni.ResponseWriter, latency time.Duration, name string, info RequestInfo) *logrus.Entry {
+	entry = entry.WithFields(logrus.Fields{
 		"status":      res.Status(),
 		"text_status": http.StatusText(res.Status()),
 		"took":        latency,
 		fmt.Sprintf("measure#%s.latency", name): latency.Nanoseconds(),
 	})
+
+	if info.DeadlineExceeded {
+		entry = entry.WithField("deadline_exceeded", true)
+	}
+	if info.PanicValue != nil {
+		entry = entry.WithField("panic", fmt.Sprintf("%v", info.PanicValue))
+	}
+	if info.Stack != nil {
+		entry = entry.WithField("stack", string(info.Stack))
+	}
+
+	return entry
 }
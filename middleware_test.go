@@ -0,0 +1,47 @@
+package negronilogrus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/urfave/negroni"
+)
+
+// TestMiddlewareTimeoutDiscardsLateWrites exercises the realistic case
+// Middleware.Timeout targets: a handler that doesn't watch the request
+// context and keeps writing past the deadline. Run with `go test -race`
+// to confirm the deadline branch in ServeHTTP and the still-running
+// handler goroutine never touch the underlying ResponseWriter
+// concurrently.
+func TestMiddlewareTimeoutDiscardsLateWrites(t *testing.T) {
+	m := NewMiddleware()
+	m.Logger.Out = io.Discard
+	m.Timeout = 10 * time.Millisecond
+
+	handlerDone := make(chan struct{})
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		defer close(handlerDone)
+		// Ignore the request context entirely, like a legacy handler
+		// would, so it's still writing well after Timeout has elapsed.
+		time.Sleep(30 * time.Millisecond)
+		for i := 0; i < 50; i++ {
+			rw.Write([]byte("x"))
+			time.Sleep(2 * time.Millisecond)
+		}
+	})
+
+	n := negroni.New(m, handler)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+
+	n.ServeHTTP(rw, req)
+	<-handlerDone
+
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d after timeout, got %d", http.StatusServiceUnavailable, rw.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package negronilogrus
+
+import "net/http"
+
+// RequestInfo carries details about how a request finished that a custom
+// After func may want to fold into the completion log entry, beyond what
+// the negroni.ResponseWriter itself exposes.
+type RequestInfo struct {
+	// PanicValue is the recovered value of a panic() that occurred while
+	// handling the request, or nil if the handler returned normally.
+	PanicValue interface{}
+
+	// Stack is a captured stack trace. On panic it is always populated,
+	// taken where the panic was recovered, regardless of the response
+	// status actually written. Otherwise it is taken while the completion
+	// log entry is built, and only when StacktracePred returns true for
+	// the response status.
+	Stack []byte
+
+	// DeadlineExceeded is true if Middleware.Timeout elapsed before the
+	// next handler in the chain finished.
+	DeadlineExceeded bool
+}
+
+// DefaultStacktracePred is the default value of Middleware.StacktracePred.
+// It attaches a stack trace for 5xx responses (and, via ServeHTTP, for any
+// panic regardless of the status eventually written).
+func DefaultStacktracePred(status int) bool {
+	return status >= http.StatusInternalServerError
+}
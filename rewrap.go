@@ -0,0 +1,134 @@
+package negronilogrus
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/urfave/negroni"
+)
+
+// rewrapResponseWriter wraps a negroni.ResponseWriter and unconditionally
+// implements http.Hijacker, http.Flusher, http.CloseNotifier, and
+// http.Pusher, each forwarding to the underlying writer if it supports
+// the call and failing at call time (an error, or a no-op/closed-channel
+// return) otherwise. The negroni.ResponseWriter interface alone does not
+// carry those optional interfaces; rewrapping it this way means they keep
+// being assertable by handlers further down the chain instead of silently
+// disappearing, and lets ServeHTTP detect WebSocket-style hijacks to log
+// them accurately. ServeHTTP only applies it when it receives a
+// negroni.ResponseWriter.
+//
+// Note this means a type assertion like `w.(http.Hijacker)` always
+// succeeds on a *rewrapResponseWriter, even when the writer underneath
+// can't actually hijack — callers that need to know real capability
+// ahead of time must call Hijack/Push and check the returned error
+// rather than gating on the assertion alone.
+type rewrapResponseWriter struct {
+	negroni.ResponseWriter
+
+	mu            sync.Mutex
+	hijacked      bool
+	hijackedBytes int64
+	closed        chan struct{}
+}
+
+func newRewrapResponseWriter(rw negroni.ResponseWriter) *rewrapResponseWriter {
+	return &rewrapResponseWriter{ResponseWriter: rw, closed: make(chan struct{})}
+}
+
+// Hijack implements http.Hijacker, forwarding to the underlying writer if
+// it supports hijacking and wrapping the returned net.Conn to count bytes
+// transferred and to signal Closed once the connection ends.
+func (w *rewrapResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("negronilogrus: underlying ResponseWriter does not support http.Hijacker")
+	}
+	conn, bufrw, err := hj.Hijack()
+	if err != nil {
+		return conn, bufrw, err
+	}
+
+	w.mu.Lock()
+	w.hijacked = true
+	w.mu.Unlock()
+
+	return &countingConn{Conn: conn, counted: &w.hijackedBytes, closed: w.closed}, bufrw, nil
+}
+
+// Flush implements http.Flusher.
+func (w *rewrapResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify implements the deprecated http.CloseNotifier, still used by
+// some older handlers.
+func (w *rewrapResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Push implements http.Pusher.
+func (w *rewrapResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Hijacked reports whether Hijack completed successfully for this request.
+func (w *rewrapResponseWriter) Hijacked() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.hijacked
+}
+
+// HijackedBytes reports the number of bytes read from or written to the
+// net.Conn returned by Hijack so far. Bytes moved through the
+// *bufio.ReadWriter returned alongside it are not counted.
+func (w *rewrapResponseWriter) HijackedBytes() int64 {
+	return atomic.LoadInt64(&w.hijackedBytes)
+}
+
+// Closed is closed once a hijacked connection has been closed by the
+// handler, signalling that it is safe to emit the completion log entry.
+func (w *rewrapResponseWriter) Closed() <-chan struct{} {
+	return w.closed
+}
+
+// countingConn wraps a hijacked net.Conn, counting bytes transferred in
+// either direction and closing a channel once the connection is closed, so
+// callers can defer logging until a WebSocket (or other hijacked) session
+// actually ends.
+type countingConn struct {
+	net.Conn
+	counted *int64
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(c.counted, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(c.counted, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { close(c.closed) })
+	return err
+}
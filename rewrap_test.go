@@ -0,0 +1,50 @@
+package negronilogrus
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urfave/negroni"
+)
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	server, _ := net.Pipe()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+// TestBodyLogPolicyPreservesHijacker guards against the regression where
+// wrapping the ResponseWriter for body capture silently dropped
+// http.Hijacker (and Flusher/CloseNotifier/Pusher) because
+// countingResponseWriter embedded negroni.ResponseWriter as an interface
+// instead of forwarding to the concrete writer underneath it — undoing
+// rewrapResponseWriter's hijack preservation the moment BodyLogPolicy was
+// also configured.
+func TestBodyLogPolicyPreservesHijacker(t *testing.T) {
+	m := NewMiddleware()
+	m.Logger.Out = io.Discard
+	m.BodyLogPolicy = &BodyLogPolicy{MaxBytes: 1024}
+
+	var gotHijacker bool
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		_, gotHijacker = rw.(http.Hijacker)
+	})
+
+	n := negroni.New(m, handler)
+
+	rec := hijackableRecorder{httptest.NewRecorder()}
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+
+	n.ServeHTTP(rec, req)
+
+	if !gotHijacker {
+		t.Fatal("expected the ResponseWriter passed to the handler to still implement http.Hijacker when BodyLogPolicy is set")
+	}
+}
@@ -0,0 +1,116 @@
+package negronilogrus
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SamplingPolicy decides, before Before runs, whether a request should be
+// logged at all and, if so, at what level the "started"/"completed" log
+// lines should be emitted.
+type SamplingPolicy interface {
+	ShouldLog(r *http.Request) (log bool, level logrus.Level)
+}
+
+// SamplingPolicyFunc adapts a plain func to SamplingPolicy.
+type SamplingPolicyFunc func(r *http.Request) (bool, logrus.Level)
+
+// ShouldLog calls f.
+func (f SamplingPolicyFunc) ShouldLog(r *http.Request) (bool, logrus.Level) {
+	return f(r)
+}
+
+// RateSampler is a SamplingPolicy that logs at most PerSecond requests per
+// second, always at Level, and drops the rest.
+type RateSampler struct {
+	PerSecond int
+	Level     logrus.Level
+
+	mu      sync.Mutex
+	second  int64
+	counted int
+	now     func() time.Time
+}
+
+// NewRateSampler returns a RateSampler that allows at most perSecond
+// requests through per second, logged at level.
+func NewRateSampler(perSecond int, level logrus.Level) *RateSampler {
+	return &RateSampler{PerSecond: perSecond, Level: level, now: time.Now}
+}
+
+// ShouldLog implements SamplingPolicy.
+func (s *RateSampler) ShouldLog(r *http.Request) (bool, logrus.Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+
+	if sec := now().Unix(); sec != s.second {
+		s.second = sec
+		s.counted = 0
+	}
+	s.counted++
+
+	return s.counted <= s.PerSecond, s.Level
+}
+
+// StatusLevel is a built-in level promoter suitable for
+// Middleware.CompletionLevel: it logs 2xx/3xx responses at Info, 4xx at
+// Warn, and 5xx at Error, so that client and server errors stand out in
+// log aggregation without any per-request configuration.
+func StatusLevel(status int) logrus.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return logrus.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return logrus.WarnLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
+
+// excludeRule is one entry registered via ExcludeURL/ExcludeURLForMethods.
+// pattern is matched against the request path as an exact string, as a
+// path.Match glob (e.g. "/api/*/status"), or, if re is set (pattern
+// started with "^"), as an anchored regexp (e.g.
+// "^/api/v[0-9]+/health$"); an empty methods list matches every HTTP
+// method.
+type excludeRule struct {
+	pattern string
+	methods []string
+	re      *regexp.Regexp
+}
+
+func (e excludeRule) matches(r *http.Request) bool {
+	if len(e.methods) > 0 {
+		methodMatch := false
+		for _, meth := range e.methods {
+			if strings.EqualFold(meth, r.Method) {
+				methodMatch = true
+				break
+			}
+		}
+		if !methodMatch {
+			return false
+		}
+	}
+
+	if e.re != nil {
+		return e.re.MatchString(r.URL.Path)
+	}
+
+	if e.pattern == r.URL.Path {
+		return true
+	}
+	matched, err := path.Match(e.pattern, r.URL.Path)
+	return err == nil && matched
+}
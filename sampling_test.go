@@ -0,0 +1,41 @@
+package negronilogrus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urfave/negroni"
+)
+
+func TestExcludeURLSupportsAnchoredRegexp(t *testing.T) {
+	m := NewMiddleware()
+	if err := m.ExcludeURL("^/api/v[0-9]+/health$"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var called bool
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		called = true
+	})
+	n := negroni.New(m, handler)
+
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/health", nil))
+	if !called {
+		t.Fatal("expected the handler to run")
+	}
+
+	if !m.excludeURLs[0].matches(httptest.NewRequest(http.MethodGet, "/api/v2/health", nil)) {
+		t.Fatal("expected the regexp pattern to match /api/v2/health")
+	}
+	if m.excludeURLs[0].matches(httptest.NewRequest(http.MethodGet, "/api/v2/health/extra", nil)) {
+		t.Fatal("expected the anchored regexp not to match /api/v2/health/extra")
+	}
+}
+
+func TestExcludeURLRejectsInvalidRegexp(t *testing.T) {
+	m := NewMiddleware()
+	if err := m.ExcludeURL("^("); err == nil {
+		t.Fatal("expected an error for an invalid regexp pattern")
+	}
+}
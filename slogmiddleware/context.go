@@ -0,0 +1,28 @@
+package slogmiddleware
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxLoggerMarker struct{}
+
+var ctxLoggerKey = &ctxLoggerMarker{}
+
+// Extract takes the call-scoped *slog.Logger from context.
+//
+// If the slogmiddleware wasn't used, slog.Default() is returned. This
+// makes it safe to use regardless.
+func Extract(ctx context.Context) *slog.Logger {
+	l, ok := ctx.Value(ctxLoggerKey).(*slog.Logger)
+	if !ok || l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// ToContext adds the *slog.Logger to the context for extraction later.
+// Returning the new context that has been created.
+func ToContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxLoggerKey, logger)
+}
@@ -0,0 +1,179 @@
+// Package slogmiddleware is a log/slog-backed equivalent of the top-level
+// negronilogrus.Middleware, for users migrating to Go's standard
+// structured logging package without giving up this module's negroni
+// integration, context propagation, and excluded-URL handling.
+package slogmiddleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	negronilogrus "github.com/Bestowinc/negroni-logrus"
+	"github.com/urfave/negroni"
+)
+
+// Middleware is a middleware handler that logs the request as it goes in
+// and the response as it goes out, backed by a *slog.Logger.
+type Middleware struct {
+	// Logger is the slog.Logger instance used to log messages with the
+	// Logger middleware.
+	Logger *slog.Logger
+	// Name is the name of the application as recorded in latency metrics.
+	Name   string
+	Before func(*slog.Logger, *http.Request, string) []slog.Attr
+	After  func(*slog.Logger, negroni.ResponseWriter, time.Duration, string) []slog.Attr
+
+	logStarting bool
+
+	// Exclude URLs from logging
+	excludeURLs []string
+}
+
+// NewMiddleware returns a new *Middleware backed by slog.Default().
+func NewMiddleware() *Middleware {
+	return NewMiddlewareFromLogger(slog.Default(), "web")
+}
+
+// NewMiddlewareFromLogger builds a *Middleware which writes to a given
+// *slog.Logger.
+func NewMiddlewareFromLogger(logger *slog.Logger, name string) *Middleware {
+	return &Middleware{
+		Logger: logger,
+		Name:   name,
+		Before: DefaultBefore,
+		After:  DefaultAfter,
+
+		logStarting: true,
+	}
+}
+
+// SetLogStarting accepts a bool to control the logging of "started
+// handling request" prior to passing to the next middleware.
+func (m *Middleware) SetLogStarting(v bool) {
+	m.logStarting = v
+}
+
+// ExcludeURL adds a new URL u to be ignored during logging. The URL u is
+// parsed, hence the returned error.
+func (m *Middleware) ExcludeURL(u string) error {
+	if _, err := url.Parse(u); err != nil {
+		return err
+	}
+	m.excludeURLs = append(m.excludeURLs, u)
+	return nil
+}
+
+// ExcludedURLs returns the list of excluded URLs for this middleware.
+func (m *Middleware) ExcludedURLs() []string {
+	return m.excludeURLs
+}
+
+func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if m.Before == nil {
+		m.Before = DefaultBefore
+	}
+
+	if m.After == nil {
+		m.After = DefaultAfter
+	}
+
+	for _, u := range m.excludeURLs {
+		if r.URL.Path == u {
+			next(rw, r)
+			return
+		}
+	}
+
+	start := time.Now()
+
+	// Try to get the real IP
+	remoteAddr := r.RemoteAddr
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		remoteAddr = realIP
+	}
+
+	logger := m.Logger
+
+	if reqID := r.Header.Get("X-Request-Id"); reqID != "" {
+		logger = logger.With("request_id", reqID)
+	}
+
+	logger = logger.With(attrsToArgs(m.Before(logger, r, remoteAddr))...)
+
+	if m.logStarting {
+		logger.Info("started handling request")
+	}
+
+	newCtx := ToContext(r.Context(), logger)
+	newCtx = negronilogrus.ToContextAny(newCtx, asLogger(logger))
+	next(rw, r.WithContext(newCtx))
+
+	latency := time.Since(start)
+	res, ok := rw.(negroni.ResponseWriter)
+	if !ok {
+		// see negronilogrus.Middleware.ServeHTTP for why this fallback
+		// exists: outer wrappers such as OpenCensus hide
+		// negroni.ResponseWriter behind a private struct.
+		rw = negronilogrus.ExtractWriter(r.Context())
+		res, ok = rw.(negroni.ResponseWriter)
+	}
+	if ok {
+		// extract the logger stored in newCtx by ToContext above.
+		log := Extract(newCtx)
+		log.With(attrsToArgs(m.After(log, res, latency, m.Name))...).Info("completed handling request")
+	}
+}
+
+func attrsToArgs(attrs []slog.Attr) []interface{} {
+	args := make([]interface{}, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	return args
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func asLogger(l *slog.Logger) negronilogrus.Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) With(args ...interface{}) negronilogrus.Logger {
+	return slogLogger{l: s.l.With(args...)}
+}
+
+func (s slogLogger) Info(msg string, args ...interface{})  { s.l.Info(msg, args...) }
+func (s slogLogger) Warn(msg string, args ...interface{})  { s.l.Warn(msg, args...) }
+func (s slogLogger) Error(msg string, args ...interface{}) { s.l.Error(msg, args...) }
+
+// BeforeFunc is the func type used to produce slog.Attr fields prior to
+// calling the next func in the middleware chain.
+type BeforeFunc func(*slog.Logger, *http.Request, string) []slog.Attr
+
+// AfterFunc is the func type used to produce slog.Attr fields after
+// calling the next func in the middleware chain.
+type AfterFunc func(*slog.Logger, negroni.ResponseWriter, time.Duration, string) []slog.Attr
+
+// DefaultBefore is the default func assigned to *Middleware.Before.
+func DefaultBefore(logger *slog.Logger, req *http.Request, remoteAddr string) []slog.Attr {
+	return []slog.Attr{
+		slog.String("request", req.RequestURI),
+		slog.String("method", req.Method),
+		slog.String("remote", remoteAddr),
+	}
+}
+
+// DefaultAfter is the default func assigned to *Middleware.After.
+func DefaultAfter(logger *slog.Logger, res negroni.ResponseWriter, latency time.Duration, name string) []slog.Attr {
+	return []slog.Attr{
+		slog.Int("status", res.Status()),
+		slog.String("text_status", http.StatusText(res.Status())),
+		slog.Duration("took", latency),
+		slog.Int64(fmt.Sprintf("measure#%s.latency", name), latency.Nanoseconds()),
+	}
+}
@@ -0,0 +1,75 @@
+package slogmiddleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"log/slog"
+
+	"github.com/urfave/negroni"
+)
+
+func TestMiddlewareLogsStartAndCompletion(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	m := NewMiddlewareFromLogger(logger, "web")
+
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	n := negroni.New(m, handler)
+
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/brew", nil))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected a started and a completed log line, got %d: %s", len(lines), buf.String())
+	}
+
+	var started, completed map[string]interface{}
+	if err := json.Unmarshal(lines[0], &started); err != nil {
+		t.Fatalf("unexpected error unmarshaling started line: %v", err)
+	}
+	if err := json.Unmarshal(lines[1], &completed); err != nil {
+		t.Fatalf("unexpected error unmarshaling completed line: %v", err)
+	}
+
+	if started["msg"] != "started handling request" {
+		t.Fatalf("expected a started line, got %v", started)
+	}
+	if completed["msg"] != "completed handling request" {
+		t.Fatalf("expected a completed line, got %v", completed)
+	}
+	if status, ok := completed["status"].(float64); !ok || int(status) != http.StatusTeapot {
+		t.Fatalf("expected status %d in completion line, got %v", http.StatusTeapot, completed["status"])
+	}
+}
+
+func TestMiddlewareExcludeURLSkipsLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	m := NewMiddlewareFromLogger(logger, "web")
+	if err := m.ExcludeURL("/healthz"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var called bool
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		called = true
+	})
+	n := negroni.New(m, handler)
+
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if !called {
+		t.Fatal("expected the handler to still run for an excluded URL")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no log output for an excluded URL, got %q", buf.String())
+	}
+}
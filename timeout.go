@@ -0,0 +1,101 @@
+package negronilogrus
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/urfave/negroni"
+)
+
+// timeoutResponseWriter wraps a negroni.ResponseWriter so that a
+// deadline-exceeded write from Middleware.ServeHTTP can never race with a
+// still-running handler goroutine writing to the same underlying writer.
+// Every Write/WriteHeader, from either goroutine, goes through the same
+// mutex; once timeout fires, writes arriving after it are silently
+// dropped instead of reaching the real ResponseWriter — the same
+// divert-late-writes approach net/http.TimeoutHandler uses for its
+// buffering proxy.
+type timeoutResponseWriter struct {
+	negroni.ResponseWriter
+
+	mu       sync.Mutex
+	timedOut bool
+}
+
+func newTimeoutResponseWriter(rw negroni.ResponseWriter) *timeoutResponseWriter {
+	return &timeoutResponseWriter{ResponseWriter: rw}
+}
+
+func (w *timeoutResponseWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *timeoutResponseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Hijack implements http.Hijacker, forwarding to the underlying writer
+// the same way rewrapResponseWriter and countingResponseWriter do. It is
+// refused once timeout has fired, since a hijack that raced the timeout
+// branch would hand the handler a connection this writer has already
+// considered done with.
+func (w *timeoutResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, fmt.Errorf("negronilogrus: ResponseWriter already timed out")
+	}
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("negronilogrus: underlying ResponseWriter does not support http.Hijacker")
+	}
+	return hj.Hijack()
+}
+
+// CloseNotify implements the deprecated http.CloseNotifier.
+func (w *timeoutResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Push implements http.Pusher, refused once timeout has fired for the
+// same reason Write is.
+func (w *timeoutResponseWriter) Push(target string, opts *http.PushOptions) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return http.ErrNotSupported
+	}
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// timeout writes status if nothing has been written yet, then marks the
+// writer as timed out so any write the handler goroutine is still in the
+// middle of — or starts afterwards — is discarded rather than racing with
+// this one.
+func (w *timeoutResponseWriter) timeout(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.Written() {
+		w.ResponseWriter.WriteHeader(status)
+	}
+	w.timedOut = true
+}
@@ -0,0 +1,39 @@
+package negronilogrus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/urfave/negroni"
+)
+
+// TestTimeoutResponseWriterForwardsHijacker guards against the regression
+// where timeoutResponseWriter, unlike countingResponseWriter and
+// rewrapResponseWriter, embedded negroni.ResponseWriter without forwarding
+// http.Hijacker/http.Pusher/http.CloseNotifier — silently dropping hijack
+// support the moment Middleware.Timeout was configured alongside it.
+func TestTimeoutResponseWriterForwardsHijacker(t *testing.T) {
+	m := NewMiddleware()
+	m.Logger.Out = io.Discard
+	m.Timeout = time.Second
+	m.BodyLogPolicy = &BodyLogPolicy{MaxBytes: 1024}
+
+	var gotHijacker bool
+	handler := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		_, gotHijacker = rw.(http.Hijacker)
+	})
+
+	n := negroni.New(m, handler)
+
+	rec := hijackableRecorder{httptest.NewRecorder()}
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+
+	n.ServeHTTP(rec, req)
+
+	if !gotHijacker {
+		t.Fatal("expected the ResponseWriter passed to the handler to still implement http.Hijacker when Timeout is set")
+	}
+}